@@ -1,11 +1,18 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"log"
 	"math/rand"
+	"os"
+	"os/signal"
+	"sort"
 	"sync"
 	"sync/atomic"
+	"syscall"
+	"text/tabwriter"
 	"time"
 )
 
@@ -20,6 +27,27 @@ type Task[RType any] struct {
 	description string
 }
 
+// TaskState is the lifecycle state of a submitted task
+type TaskState string
+
+const (
+	Pending  TaskState = "Pending"
+	Running  TaskState = "Running"
+	Done     TaskState = "Done"
+	Panicked TaskState = "Panicked"
+)
+
+// TaskStatus is a point-in-time snapshot of a task's progress, used
+// by Status and Watch to report what the executor is doing
+type TaskStatus struct {
+	Name      string
+	State     TaskState
+	WorkerID  int
+	StartedAt time.Time
+	EndedAt   time.Time
+	Recovered any
+}
+
 // # Concurrent task executor
 //
 // It spawns workers depending on the **workers** field
@@ -37,22 +65,29 @@ type TaskExecutor[RType any] struct {
 	workers    int
 	mu         *sync.Mutex
 	jobcounter *atomic.Int32
+	pending    *atomic.Int32
 	wg         *sync.WaitGroup
 	jobs       chan Task[RType]
 	results    map[int32]RType
+	statuses   map[int32]*TaskStatus
 }
 
 // Initialize the task executor and make it ready to receive jobs
-// for execution
-func InitializeTaskExecutor[RType any](workers int) TaskExecutor[RType] {
+// for execution. Workers keep running until the jobs channel is
+// closed or ctx is cancelled, whichever happens first
+func InitializeTaskExecutor[RType any](ctx context.Context, workers int) TaskExecutor[RType] {
 	// Initialize our jobs channel
 	jobs := make(chan Task[RType], 10)
 	results := make(map[int32]RType)
+	statuses := make(map[int32]*TaskStatus)
 	mutex := sync.Mutex{}
 
-	// Create our jobcounter
+	// Create our jobcounter, used only to hand out unique task ids
 	jobcounter := atomic.Int32{}
 
+	// Create our pending counter, tracking tasks submitted but not yet executed
+	pending := atomic.Int32{}
+
 	// Initialize WaitGroup to wait for all workers to complete their tasks
 	var wg sync.WaitGroup
 
@@ -61,100 +96,232 @@ func InitializeTaskExecutor[RType any](workers int) TaskExecutor[RType] {
 		workers:    workers,
 		mu:         &mutex,
 		jobcounter: &jobcounter,
+		pending:    &pending,
 		wg:         &wg,
 		jobs:       jobs,
 		results:    results,
+		statuses:   statuses,
 	}
 
-	// Spawn the workers, ready for receiving jobs/tasks
+	// Spawn the workers, registering each with the WaitGroup before it
+	// starts so BlockOn can't race past a worker that hasn't checked in yet
 	for workerid := range workers {
-		go TaskWorker(&taskexecutor, workerid)
 		taskexecutor.wg.Add(1)
+		go TaskWorker(ctx, &taskexecutor, workerid)
 	}
 
 	return taskexecutor
 }
 
+// registerTask records a Pending status for a newly created task
+// under the mutex, before the task ever reaches a worker
+func (taskexecutor *TaskExecutor[RType]) registerTask(id int32, description string) {
+	taskexecutor.mu.Lock()
+	defer taskexecutor.mu.Unlock()
+
+	taskexecutor.statuses[id] = &TaskStatus{Name: description, State: Pending}
+}
+
 // Add task into our task queue
 func (taskexecutor *TaskExecutor[RType]) AddTask(job func() RType, description string) bool {
+	// Atomically hand out the next id, so concurrent callers never collide
+	id := taskexecutor.jobcounter.Add(1) - 1
+
 	// Initialize the task
-	task := Task[RType]{id: taskexecutor.jobcounter.Load(), job: job, description: description}
+	task := Task[RType]{id: id, job: job, description: description}
+	taskexecutor.registerTask(id, description)
+
+	// Mark the task pending before it ever reaches the jobs channel, so
+	// a worker can't dequeue and finish it before we've accounted for it
+	taskexecutor.pending.Add(1)
 
 	// Send the created task to the jobs channel
 	taskexecutor.jobs <- task
 
-	// Increment job counter
-	taskexecutor.jobcounter.Add(1)
-
 	return true
 }
 
-// Get the results of the executed tasks
+// SubmitBlocking adds a task to the queue, but instead of blocking
+// forever when the jobs channel is full it gives up as soon as ctx
+// is cancelled, returning ctx.Err()
+func (taskexecutor *TaskExecutor[RType]) SubmitBlocking(ctx context.Context, job func() RType, description string) error {
+	id := taskexecutor.jobcounter.Add(1) - 1
+	task := Task[RType]{id: id, job: job, description: description}
+	taskexecutor.registerTask(id, description)
+
+	// Mark the task pending before attempting the send, for the same
+	// reason as AddTask; roll it back if the send never happens
+	taskexecutor.pending.Add(1)
+
+	select {
+	case taskexecutor.jobs <- task:
+		return nil
+	case <-ctx.Done():
+		taskexecutor.pending.Add(-1)
+		return ctx.Err()
+	}
+}
+
+// Status reports the total number of tasks ever submitted and a
+// snapshot of the tasks currently in the Running state, sorted by name
+func (taskexecutor *TaskExecutor[RType]) Status() (total int, active []TaskStatus) {
+	taskexecutor.mu.Lock()
+	defer taskexecutor.mu.Unlock()
+
+	total = len(taskexecutor.statuses)
+	for _, status := range taskexecutor.statuses {
+		if status.State == Running {
+			active = append(active, *status)
+		}
+	}
+	sort.Slice(active, func(i, j int) bool {
+		return active[i].Name < active[j].Name
+	})
+
+	return total, active
+}
+
+// Watch prints a live table of in-flight tasks, with their running
+// duration, to w every d, until the executor has no pending tasks left
+func (taskexecutor *TaskExecutor[RType]) Watch(d time.Duration, w io.Writer) {
+	ticker := time.NewTicker(d)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if taskexecutor.JobsDone() {
+			return
+		}
+
+		total, active := taskexecutor.Status()
+
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(tw, "TASK\tSTATE\tDURATION\n")
+		for _, status := range active {
+			duration := time.Since(status.StartedAt).Round(time.Millisecond)
+			fmt.Fprintf(tw, "%s\t%s\t%v\n", status.Name, status.State, duration)
+		}
+		tw.Flush()
+
+		fmt.Fprintf(w, "%d tasks submitted, %d running\n\n", total, len(active))
+	}
+}
+
+// Get the results of the executed tasks. Returns a defensive copy
+// taken under the mutex so callers can't race with workers still
+// writing into the live map
 func (taskexecutor *TaskExecutor[RType]) GetResults() map[int32]RType {
-	for {	
-		return taskexecutor.results
+	taskexecutor.mu.Lock()
+	defer taskexecutor.mu.Unlock()
+
+	results := make(map[int32]RType, len(taskexecutor.results))
+	for id, result := range taskexecutor.results {
+		results[id] = result
 	}
+
+	return results
 }
 
 // Get task return type by id
 func (taskexecutor *TaskExecutor[RType]) GetResultByTaskId(taskid int32) RType {
+	taskexecutor.mu.Lock()
+	defer taskexecutor.mu.Unlock()
+
 	return taskexecutor.results[taskid]
 }
 
-// Checks whether the workers have executed all tasks
+// Checks whether the workers have executed all submitted tasks
 func (taskexecutor *TaskExecutor[RType]) JobsDone() bool {
-	return taskexecutor.jobcounter.Load() == 0
+	return taskexecutor.pending.Load() == 0
 }
 
-// Wait for workers to finish
-func (taskexecutor *TaskExecutor[RType]) BlockOn() {
-	taskexecutor.wg.Wait()
-}
+// Wait for workers to finish, or for ctx to be cancelled, whichever
+// comes first, so a caller can stop blocking on Ctrl-C even if some
+// workers are still draining
+func (taskexecutor *TaskExecutor[RType]) BlockOn(ctx context.Context) {
+	done := make(chan struct{})
+	go func() {
+		taskexecutor.wg.Wait()
+		close(done)
+	}()
 
-// Checks whether all jobs have been executed, if so
-// then closes the jobs channel, inorder to allow the 
-// background workers(goroutines) to shutdown
-func (tasksexecutor *TaskExecutor[RType]) Close() {
-	for {
-		if tasksexecutor.JobsDone() {
-			close(tasksexecutor.jobs)
-			break
-		}
+	select {
+	case <-done:
+	case <-ctx.Done():
 	}
 }
 
+// Shutdown closes the jobs channel, so the worker goroutines drain
+// whatever is left queued and exit on their own, then blocks until
+// they've all reported back to the WaitGroup, or until ctx is
+// cancelled, whichever comes first
+func (tasksexecutor *TaskExecutor[RType]) Shutdown(ctx context.Context) {
+	close(tasksexecutor.jobs)
+	tasksexecutor.BlockOn(ctx)
+}
+
 // This is the task worker, will be running in the background waiting
-// for new tasks inorder to be executed
-func TaskWorker[RType any](taskexecutor *TaskExecutor[RType], workerid int) {
-	for {
-		// The more variable refers to a bool of if the channel was closed or open
-		task, more := <-taskexecutor.jobs
+// for new tasks inorder to be executed. It exits either when the jobs
+// channel is closed or when ctx is cancelled, in both cases notifying
+// the WaitGroup so BlockOn can return
+func TaskWorker[RType any](ctx context.Context, taskexecutor *TaskExecutor[RType], workerid int) {
+	defer taskexecutor.wg.Done()
 
-		// If jobs channel is still open, meaning jobs are still coming in
-		if more {
-			// Executing the job
-			returnValue := task.job()
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("Worker", workerid, "cancelled, shutting down")
+			return
+		case task, more := <-taskexecutor.jobs:
+			// If jobs channel is still open, meaning jobs are still coming in
+			if !more {
+				log.Println("Shutting down workers")
+				return
+			}
 
-			// Lock the mutex to update the results map
 			taskexecutor.mu.Lock()
-			taskexecutor.results[task.id] = returnValue
+			status := taskexecutor.statuses[task.id]
+			status.State = Running
+			status.WorkerID = workerid
+			status.StartedAt = time.Now()
 			taskexecutor.mu.Unlock()
 
-			// Decrement job counter
-			oldjobcounter := taskexecutor.jobcounter.Load()
-			taskexecutor.jobcounter.Swap(oldjobcounter - 1)
+			// Executing the job, recovering a panic so one bad job
+			// marks its own task Panicked instead of killing the worker
+			runTask(taskexecutor, &task, status)
 
-			log.Println("Task", task.id, "has been executed by worker", workerid)
-		} else {
-			log.Println("Shutting down workers")
+			// Decrement the pending counter now the task has run
+			taskexecutor.pending.Add(-1)
 
-			// Telling our WaitGroup that a worker is done with its work
-			taskexecutor.wg.Done()
-			break
+			log.Println("Task", task.id, "has been executed by worker", workerid)
 		}
 	}
 }
 
+// runTask executes a single task's job, recovering any panic so it
+// marks the task Panicked (with the recovered value attached) instead
+// of crashing the worker goroutine, and records the result otherwise
+func runTask[RType any](taskexecutor *TaskExecutor[RType], task *Task[RType], status *TaskStatus) {
+	defer func() {
+		taskexecutor.mu.Lock()
+		status.EndedAt = time.Now()
+		taskexecutor.mu.Unlock()
+
+		if r := recover(); r != nil {
+			taskexecutor.mu.Lock()
+			status.State = Panicked
+			status.Recovered = r
+			taskexecutor.mu.Unlock()
+		}
+	}()
+
+	returnValue := task.job()
+
+	taskexecutor.mu.Lock()
+	taskexecutor.results[task.id] = returnValue
+	status.State = Done
+	taskexecutor.mu.Unlock()
+}
+
 // Sample job to execute
 func MyJob() int {
 	// To simulate a compute heavy task
@@ -163,7 +330,12 @@ func MyJob() int {
 }
 
 func main() {
-	// Implement recover to prevent panics 
+	// Root context, cancelled on SIGINT/SIGTERM/SIGQUIT so in-flight
+	// workers drain and exit instead of leaking goroutines
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM, syscall.SIGQUIT)
+	defer stop()
+
+	// Implement recover to prevent panics
 	fmt.Println()
 	log.Println("First Phase")
 
@@ -174,13 +346,13 @@ func main() {
 	}()
 
 	// Initialize a TaskExecutor
-	taskexecutor := InitializeTaskExecutor[int](10)
+	taskexecutor := InitializeTaskExecutor[int](ctx, 10)
 
 	// Generate sample jobs
 	for i := range 20 {
 		taskexecutor.AddTask(MyJob, fmt.Sprintf("This is task number %d", i))
 	}
-	
+
 	time.Sleep(3 * time.Second)
 	fmt.Println()
 	log.Println("Second phase")
@@ -189,11 +361,9 @@ func main() {
 		taskexecutor.AddTask(MyJob, fmt.Sprintf("This is task number %d", i))
 	}
 
-	// We close the job channel after job execution
-	taskexecutor.Close()
-
-	// Wait for the workers to complete
-	taskexecutor.BlockOn()
+	// We close the job channel after job execution and wait for the
+	// workers to drain whatever is left
+	taskexecutor.Shutdown(ctx)
 
 	// Display the results
 	log.Println("The results: ", taskexecutor.GetResults())
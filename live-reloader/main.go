@@ -1,13 +1,18 @@
 package main
 
 import (
+	"context"
 	"crypto/sha256"
 	"io/fs"
+	"log"
 	"os"
+	"os/signal"
 	fp "path/filepath"
 	"sync"
-	"time" 
-	"log"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
 )
 
 // Utility function for handling errors
@@ -18,7 +23,7 @@ func check(err error) {
 }
 
 // # LiveReloader
-// 
+//
 // dirpath: is the path to dir to be watched
 //
 // The filehashes: concurrent hashmap will
@@ -27,29 +32,43 @@ func check(err error) {
 //
 // # This is a live reloader application
 //
-// Will scan through files in a directory or anything
-// changes with in the directory then reloads in real
-// time, We'll spawn goroutines to look at each file
-// concurrently, then we'll use a concurrent hashmap
-// Where the key is the filepath and the value is the
-// hash of the contents in the given file
+// Watches dirpath recursively via fsnotify instead of polling, debouncing
+// bursts of CREATE/WRITE/RENAME/REMOVE events within debounce before
+// rehashing the affected file to confirm the content actually changed
 type LiveReloader struct {
 	dirpath    string
 	changes    chan string
 	filehashes *sync.Map
+	watcher    *fsnotify.Watcher
+	debounce   time.Duration
+	mu         sync.Mutex
+	ignores    []string
+	timers     map[string]*time.Timer
 }
 
-// Initialize the LiveReloader
-func New(dir string) LiveReloader {
-	// Initialize the filehashes concurrent hashmap
-	var filehashes sync.Map
-	changes := make(chan string, 10)
+// New sets up a recursive fsnotify watch on dir, coalescing bursts of
+// events on the same path within debounce before they're confirmed
+func New(dir string, debounce time.Duration) (*LiveReloader, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
 
-	return LiveReloader{
+	livereloader := &LiveReloader{
 		dirpath:    dir,
-		changes:    changes,
-		filehashes: &filehashes,
+		changes:    make(chan string, 10),
+		filehashes: &sync.Map{},
+		watcher:    watcher,
+		debounce:   debounce,
+		timers:     make(map[string]*time.Timer),
+	}
+
+	if err := livereloader.watchDirRecursive(dir); err != nil {
+		watcher.Close()
+		return nil, err
 	}
+
+	return livereloader, nil
 }
 
 // Returns the dir where the live reloading will happen
@@ -57,86 +76,189 @@ func (livereloader *LiveReloader) GetDir() string {
 	return livereloader.dirpath
 }
 
-// Read the changes channel for changed files with evidence of their file paths
-// Takes in a function and executes it each time a file's content is changed
-func (livereloader *LiveReloader) GetFileChangesFromChannel(changefunc func(filepath string)) {
+// AddIgnore registers a glob, matched against a path's base name, that
+// should never register a watch or trigger a reload, e.g. ".git",
+// "node_modules" or a binary's name
+func (livereloader *LiveReloader) AddIgnore(glob string) {
+	livereloader.mu.Lock()
+	defer livereloader.mu.Unlock()
+
+	livereloader.ignores = append(livereloader.ignores, glob)
+}
+
+func (livereloader *LiveReloader) isIgnored(path string) bool {
+	base := fp.Base(path)
+
+	livereloader.mu.Lock()
+	defer livereloader.mu.Unlock()
+
+	for _, glob := range livereloader.ignores {
+		if ok, _ := fp.Match(glob, base); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// watchDirRecursive registers a watch on dir and every non-ignored
+// subdirectory underneath it
+func (livereloader *LiveReloader) watchDirRecursive(dir string) error {
+	return fp.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !d.IsDir() {
+			return nil
+		}
+
+		if livereloader.isIgnored(path) {
+			return fs.SkipDir
+		}
+
+		return livereloader.watcher.Add(path)
+	})
+}
+
+// Run processes fsnotify events until ctx is cancelled. New directories
+// get their own watch registered on the fly since fsnotify doesn't recurse
+func (livereloader *LiveReloader) Run(ctx context.Context) {
 	for {
-		filepath := <-livereloader.changes
-		changefunc(filepath)
+		select {
+		case <-ctx.Done():
+			return
+		case err, ok := <-livereloader.watcher.Errors:
+			if !ok {
+				return
+			}
+			check(err)
+		case event, ok := <-livereloader.watcher.Events:
+			if !ok {
+				return
+			}
+			livereloader.handleEvent(event)
+		}
+	}
+}
+
+func (livereloader *LiveReloader) handleEvent(event fsnotify.Event) {
+	if livereloader.isIgnored(event.Name) {
+		return
+	}
+
+	// A freshly created directory needs its own watch registered so
+	// files added under it are picked up too
+	if event.Has(fsnotify.Create) {
+		if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+			check(livereloader.watchDirRecursive(event.Name))
+			return
+		}
+	}
+
+	if !event.Has(fsnotify.Write) && !event.Has(fsnotify.Create) &&
+		!event.Has(fsnotify.Rename) && !event.Has(fsnotify.Remove) {
+		return
+	}
+
+	// Debounce: restart the path's timer on every event in the burst,
+	// only confirming the change once things settle for debounce
+	path := event.Name
+
+	livereloader.mu.Lock()
+	if timer, exists := livereloader.timers[path]; exists {
+		timer.Stop()
 	}
+	livereloader.timers[path] = time.AfterFunc(livereloader.debounce, func() {
+		livereloader.confirmChange(path)
+	})
+	livereloader.mu.Unlock()
 }
 
-// Goroutine that will read file, hash the contents and then
-// cache the its hash in a concurrent map where key is the 
-// path to file, and the value is its content hash, 
-// using sha256 for the hashing
-func MonitorFileChanges(livereloader *LiveReloader, path string) {
-	// Read the file
+// confirmChange rehashes path once its debounce window has settled,
+// using sha256 for the hashing, and only emits on the changes channel
+// if the content actually differs from what was last seen
+func (livereloader *LiveReloader) confirmChange(path string) {
+	livereloader.mu.Lock()
+	delete(livereloader.timers, path)
+	livereloader.mu.Unlock()
+
 	c, err := os.ReadFile(path)
-	check(err)
+	if err != nil {
+		// Removed/renamed files can't be rehashed, report the path as-is
+		livereloader.changes <- path
+		return
+	}
 
-	// The hashing step
 	h := sha256.New()
 	h.Write(c)
-	bs := h.Sum(nil)
- 
-	// Check if the key exists in concurrent map
+	bs := string(h.Sum(nil))
+
 	hashvalue, exists := livereloader.filehashes.Load(path)
+	livereloader.filehashes.Store(path, bs)
 
-	// Check if the previous hash is the same, and hashvalue from map
-	// Is equal to current content hash...
-	if exists {
-		if hashvalue != string(bs) {
-			livereloader.changes <- path
-		}
+	if !exists || hashvalue != bs {
+		livereloader.changes <- path
 	}
-
-	// Store the path and hash in concurrent hashmap
-	livereloader.filehashes.Store(path, string(bs))
 }
 
-// This will be used in the filepath dir walker
-func VisitDir(livereloader *LiveReloader, path string, d fs.DirEntry, err error) error {
-	if err != nil {
-		return err
+// Close stops every in-flight debounce timer, so none of them can fire
+// after the changes channel has no reader left, and shuts down the
+// underlying watcher
+func (livereloader *LiveReloader) Close() error {
+	livereloader.mu.Lock()
+	for path, timer := range livereloader.timers {
+		timer.Stop()
+		delete(livereloader.timers, path)
 	}
+	livereloader.mu.Unlock()
 
-	// Check whether the given path is a directory or not
-	if !d.IsDir() {
-		// Spawn a goroutine for each file path
-		go MonitorFileChanges(livereloader, path)
-	}
+	return livereloader.watcher.Close()
+}
 
-	return nil
+// Read the changes channel for changed files with evidence of their file paths
+// Takes in a function and executes it each time a file's content is changed,
+// returning once ctx is cancelled so callers can shut the reader down cleanly
+func (livereloader *LiveReloader) GetFileChangesFromChannel(ctx context.Context, changefunc func(filepath string)) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case filepath := <-livereloader.changes:
+			changefunc(filepath)
+		}
+	}
 }
 
 func main() {
-	// Intialize the LiveReloader
-	livereloader := New("../")
+	// Root context, cancelled on SIGINT/SIGTERM/SIGQUIT so the reloader
+	// can be stopped without leaking the watcher or the reader goroutine
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM, syscall.SIGQUIT)
+	defer stop()
+
+	// Intialize the LiveReloader, debouncing bursts of events within 200ms
+	livereloader, err := New("../", 200*time.Millisecond)
+	if err != nil {
+		log.Fatal("Failed to start the Live Reloader: ", err)
+	}
+	defer livereloader.Close()
+
+	livereloader.AddIgnore(".git")
+	livereloader.AddIgnore("node_modules")
+	livereloader.AddIgnore("*.exe")
+	livereloader.AddIgnore("*.bin")
+
+	// Start processing fsnotify events
+	go livereloader.Run(ctx)
 
 	// Start the changes reader
-	go livereloader.GetFileChangesFromChannel(func(filepath string) {
+	go livereloader.GetFileChangesFromChannel(ctx, func(filepath string) {
 		log.Println("Changed: ", filepath)
 		log.Println("Rerunning application")
 	})
- 
-	// Initialize the ticker
-	ticker := time.NewTicker(time.Second * 1)
-	done := make(chan bool)
 
 	log.Println("Starting the Live Reloader")
 
-	// Run the live reloader
-	// Add a ticker with an interval of 1 second
-	for {
-		select {
-		case <-done:
-			continue
-		case <-ticker.C:
-			// Start the walkdir
-			fp.WalkDir(livereloader.GetDir(), func(path string, d fs.DirEntry, err error) error {
-				return VisitDir(&livereloader, path, d, err)
-			})
-		}
-	}
+	<-ctx.Done()
+	log.Println("Shutting down Live Reloader")
 }
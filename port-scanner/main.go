@@ -1,23 +1,84 @@
 package main
 
 import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"flag"
 	"fmt"
+	"log"
 	"net"
+	"os"
+	"os/signal"
 	"sort"
+	"strings"
 	"sync"
+	"syscall"
 	"time"
+
+	"golang.org/x/time/rate"
 )
 
 type ScanResult struct {
-	Port    int
-	Open    bool
-	Service string
+	Port int  `json:"port"`
+	Open bool `json:"open"`
+	// Refused is set when a closed port actively refused the connection,
+	// as opposed to the dial timing out or failing to route - evidence
+	// the host itself is up even though this particular port isn't
+	Refused bool   `json:"refused,omitempty"`
+	Service string `json:"service"`
+	Banner  string `json:"banner,omitempty"`
+	Version string `json:"version,omitempty"`
 }
 
 type PortScanner struct {
 	host    string
 	timeout time.Duration
 	workers int
+	probes  bool
+	limiter *rate.Limiter
+}
+
+// PortScannerOption configures a PortScanner at construction time
+type PortScannerOption func(*PortScanner)
+
+// WithProbes enables or disables the active banner-grabbing step that
+// normally runs after a successful dial. Probes are enabled by default
+func WithProbes(enabled bool) PortScannerOption {
+	return func(ps *PortScanner) {
+		ps.probes = enabled
+	}
+}
+
+// WithRateLimiter caps how fast ScanPort dials out. Shared across
+// several PortScanners (see HostScanner) this bounds the combined rate
+// of an entire sweep instead of just one host's
+func WithRateLimiter(limiter *rate.Limiter) PortScannerOption {
+	return func(ps *PortScanner) {
+		ps.limiter = limiter
+	}
+}
+
+// textGreetingPorts send their banner unprompted as soon as a client connects
+var textGreetingPorts = map[int]bool{
+	21:  true,
+	22:  true,
+	25:  true,
+	110: true,
+	143: true,
+}
+
+// httpsPorts need a TLS handshake before the HTTP request can be sent
+var httpsPorts = map[int]bool{
+	443:  true,
+	8443: true,
+}
+
+// httpPorts are probed with a plaintext HEAD request
+var httpPorts = map[int]bool{
+	80:   true,
+	8080: true,
 }
 
 var commonPorts = map[int]string{
@@ -38,59 +99,159 @@ var commonPorts = map[int]string{
 	5900: "VNC",
 }
 
-func NewPortScanner(host string, timeout time.Duration, workers int) *PortScanner {
-	return &PortScanner {
-		host, 
-		timeout, 
-		workers,
+func NewPortScanner(host string, timeout time.Duration, workers int, opts ...PortScannerOption) *PortScanner {
+	ps := &PortScanner{
+		host:    host,
+		timeout: timeout,
+		workers: workers,
+		probes:  true,
+	}
+
+	for _, opt := range opts {
+		opt(ps)
 	}
+
+	return ps
 }
 
-func (ps *PortScanner) ScanPort(port int) ScanResult {
+// ScanPort dials a single port, bailing out early if ctx is cancelled
+// before or during the dial. On success, and unless probes were
+// disabled via WithProbes(false), it grabs a banner from the service
+func (ps *PortScanner) ScanPort(ctx context.Context, port int) ScanResult {
 	address := fmt.Sprintf("%s:%d", ps.host, port)
-	conn, err := net.DialTimeout("tcp", address, ps.timeout)
 
-	result := ScanResult {
-		Port: port, 
-		Open: false, 
-		Service: commonPorts[port], 
+	result := ScanResult{
+		Port:    port,
+		Open:    false,
+		Service: commonPorts[port],
+	}
+
+	if ps.limiter != nil {
+		if err := ps.limiter.Wait(ctx); err != nil {
+			return result
+		}
 	}
 
+	dialer := net.Dialer{Timeout: ps.timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+
 	if err == nil {
-		result.Open = true 
+		result.Open = true
+
+		if ps.probes {
+			result.Banner, result.Version = ps.Grab(conn, port)
+		}
+
 		conn.Close()
+	} else if errors.Is(err, syscall.ECONNREFUSED) {
+		// A refusal means something answered on the host's behalf, even
+		// though this port is closed - unlike a timeout or routing error
+		result.Refused = true
 	}
 
 	return result
 }
 
-func (ps *PortScanner) ScanRange(startPort, endPort int) []ScanResult {
+// Grab fingerprints the service behind an already-open connection: text
+// protocols like SSH/SMTP/FTP/POP3/IMAP send their greeting unprompted,
+// HTTP(S) is probed with a minimal HEAD request to read the Server
+// header, and anything else falls back to a passive read
+func (ps *PortScanner) Grab(conn net.Conn, port int) (banner string, version string) {
+	deadline := time.Now().Add(ps.timeout)
+
+	switch {
+	case textGreetingPorts[port]:
+		conn.SetReadDeadline(deadline)
+		buf := make([]byte, 256)
+		if n, err := conn.Read(buf); err == nil {
+			banner = strings.TrimSpace(string(buf[:n]))
+		}
+
+	case httpsPorts[port]:
+		tlsConn := tls.Client(conn, &tls.Config{InsecureSkipVerify: true, ServerName: ps.host})
+		tlsConn.SetDeadline(deadline)
+		if err := tlsConn.Handshake(); err == nil {
+			banner, version = ps.grabHTTPServer(tlsConn, deadline)
+		}
+
+	case httpPorts[port]:
+		banner, version = ps.grabHTTPServer(conn, deadline)
+
+	default:
+		conn.SetReadDeadline(deadline)
+		buf := make([]byte, 512)
+		if n, err := conn.Read(buf); err == nil && n > 0 {
+			banner = strings.TrimSpace(string(buf[:n]))
+		}
+	}
+
+	return banner, version
+}
+
+// grabHTTPServer sends a minimal HEAD request over conn and parses the
+// Server response header, splitting off a version if one is present
+// (e.g. "nginx/1.25.3" -> server "nginx", version "1.25.3")
+func (ps *PortScanner) grabHTTPServer(conn net.Conn, deadline time.Time) (server string, version string) {
+	conn.SetDeadline(deadline)
+	fmt.Fprintf(conn, "HEAD / HTTP/1.0\r\nHost: %s\r\n\r\n", ps.host)
+
+	reader := bufio.NewReader(conn)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			break
+		}
+
+		line = strings.TrimSpace(line)
+		if line == "" {
+			break
+		}
+
+		name, value, found := strings.Cut(line, ":")
+		if found && strings.EqualFold(strings.TrimSpace(name), "server") {
+			server = strings.TrimSpace(value)
+			if product, ver, ok := strings.Cut(server, "/"); ok {
+				server, version = product, ver
+			}
+			break
+		}
+	}
+
+	return server, version
+}
+
+func (ps *PortScanner) ScanRange(ctx context.Context, startPort, endPort int) []ScanResult {
 	// Create channels
-	jobs := make(chan int, endPort - startPort+1)
+	jobs := make(chan int, endPort-startPort+1)
 	results := make(chan ScanResult, endPort-startPort+1)
 
-	// Create a waitGroup to wait 
+	// Create a waitGroup to wait
 	var wg sync.WaitGroup
 
-	// Start worker goroutines 
+	// Start worker goroutines
 	for i := 0; i < ps.workers; i++ {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
 			for port := range jobs {
-				results <- ps.ScanPort(port)
+				results <- ps.ScanPort(ctx, port)
 			}
 		}()
 	}
 
-	// Send the jobs to workers 
+	// Send the jobs to workers, stopping early if ctx is cancelled
 	go func() {
+		defer close(jobs)
 		for port := startPort; port <= endPort; port++ {
-			jobs <- port
+			select {
+			case jobs <- port:
+			case <-ctx.Done():
+				return
+			}
 		}
 	}()
 
-	// Close results channel when all workers are done 
+	// Close results channel when all workers are done
 	go func() {
 		wg.Wait()
 		close(results)
@@ -110,8 +271,8 @@ func (ps *PortScanner) ScanRange(startPort, endPort int) []ScanResult {
 	return scanResults
 }
 
-// ScanCommonPorts method scans only 
-func (ps *PortScanner) scanCommonPorts() []ScanResult {
+// ScanCommonPorts method scans only
+func (ps *PortScanner) scanCommonPorts(ctx context.Context) []ScanResult {
 	ports := make([]int, 0, len(commonPorts))
 	for port := range commonPorts {
 		ports = append(ports, port)
@@ -122,33 +283,37 @@ func (ps *PortScanner) scanCommonPorts() []ScanResult {
 	results := make(chan ScanResult, len(ports))
 
 	var wg sync.WaitGroup
-	// Start the workers 
+	// Start the workers
 	for i := 0; i < ps.workers; i++ {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
 			for port := range jobs {
-				results <- ps.ScanPort(port)
+				results <- ps.ScanPort(ctx, port)
 			}
 		}()
 	}
 
-	// Send jobs 
+	// Send jobs, stopping early if ctx is cancelled
 	go func() {
+		defer close(jobs)
 		for _, port := range ports {
-			jobs <- port 
+			select {
+			case jobs <- port:
+			case <-ctx.Done():
+				return
+			}
 		}
-		close(jobs)
 	}()
 
-	// Wait for workers to complete and close 
+	// Wait for workers to complete and close
 	go func() {
 		wg.Wait()
 		close(results)
 	}()
 
-	// Collect the results 
-	var scanResults []ScanResult 
+	// Collect the results
+	var scanResults []ScanResult
 	for scanResult := range results {
 		scanResults = append(scanResults, scanResult)
 	}
@@ -157,42 +322,46 @@ func (ps *PortScanner) scanCommonPorts() []ScanResult {
 		return scanResults[i].Port < scanResults[j].Port
 	})
 
-	return scanResults 
+	return scanResults
 }
 
-// ScanSpecificPorts scans a list of specific ports 
-func (ps *PortScanner) ScanSpecificPorts(ports []int) []ScanResult {
+// ScanSpecificPorts scans a list of specific ports
+func (ps *PortScanner) ScanSpecificPorts(ctx context.Context, ports []int) []ScanResult {
 	jobs := make(chan int, len(ports))
 	results := make(chan ScanResult, len(ports))
 
 	var wg sync.WaitGroup
 
-	// Spawn workers 
+	// Spawn workers
 	for i := 0; i < ps.workers; i++ {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
 			for port := range jobs {
-				results <- ps.ScanPort(port)
+				results <- ps.ScanPort(ctx, port)
 			}
 		}()
 	}
 
-	// Send the jobs 
+	// Send the jobs, stopping early if ctx is cancelled
 	go func() {
+		defer close(jobs)
 		for _, port := range ports {
-			jobs <- port
+			select {
+			case jobs <- port:
+			case <-ctx.Done():
+				return
+			}
 		}
-		close(jobs)
 	}()
 
-	// Wait and close after completion 
+	// Wait and close after completion
 	go func() {
 		wg.Wait()
 		close(results)
 	}()
 
-	// Collect the results 
+	// Collect the results
 	scanResults := make([]ScanResult, 0, len(ports))
 	for scanResult := range results {
 		scanResults = append(scanResults, scanResult)
@@ -205,14 +374,14 @@ func (ps *PortScanner) ScanSpecificPorts(ports []int) []ScanResult {
 	return scanResults
 }
 
-// PrintResults displays scan results 
+// PrintResults displays scan results
 func PrintResults(results []ScanResult, showClosed bool) {
 	fmt.Println("\n" + "===============================================================")
 	fmt.Println("PORT SCAN RESULTS")
 	fmt.Println("===============================================================")
 
-	openCount := 0  
-	closedCount := 0 
+	openCount := 0
+	closedCount := 0
 
 	for _, result := range results {
 		if result.Open {
@@ -222,8 +391,15 @@ func PrintResults(results []ScanResult, showClosed bool) {
 				service = "Unknown"
 			}
 			fmt.Printf("Port %d is OPEN - %s\n", result.Port, service)
+			if result.Banner != "" {
+				if result.Version != "" {
+					fmt.Printf("    Banner: %s (version %s)\n", result.Banner, result.Version)
+				} else {
+					fmt.Printf("    Banner: %s\n", result.Banner)
+				}
+			}
 		} else {
-			closedCount++ 
+			closedCount++
 			if showClosed {
 				fmt.Printf("Port %d is CLOSED\n", result.Port)
 			}
@@ -237,37 +413,67 @@ func PrintResults(results []ScanResult, showClosed bool) {
 }
 
 func main() {
-	// Configuration 
-	host := "localhost"
-	timeout := 500 * time.Millisecond
-	workers := 100 
-
-	scanner := NewPortScanner(host, timeout, workers)
-
-	fmt.Printf("Starting port scan on %s...\n", host)
-	fmt.Printf("Workers: %d | Timeout: %v\n", workers, timeout)
-
-	// Scan Common ports  
-	fmt.Println("\n--- Scanning Common Ports ---")
-	start := time.Now()
-	results := scanner.scanCommonPorts()
-	elapsed := time.Since(start)
-	PrintResults(results, false)
-	fmt.Printf("Scan completed in %v\n", elapsed)
-
-	// Usage 2: Scan a range of ports
-	start = time.Now()
-	results = scanner.ScanRange(1, 1024)
-	elapsed = time.Since(start)
-	PrintResults(results, false)
-	fmt.Printf("Scan completed in %v\n", elapsed)
-
-	// Usage 3: Scan specific ports 
-	specificPorts := []int{22, 80, 443, 3000, 5432}
-	fmt.Println("\n--- Scanning Specific Ports ---")
-	start = time.Now()
-	results = scanner.ScanSpecificPorts(specificPorts)
-	elapsed = time.Since(start)
-	PrintResults(results, true)
-	fmt.Printf("Scan completed in %v\n", elapsed)
+	// Root context, cancelled on SIGINT/SIGTERM/SIGQUIT so a scan in
+	// flight can be interrupted without leaving sockets half-open
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM, syscall.SIGQUIT)
+	defer stop()
+
+	target := flag.String("target", "localhost", "host, CIDR (e.g. 192.168.1.0/24), or path to a newline-delimited host list")
+	startPort := flag.Int("start-port", 1, "first port in the range to scan")
+	endPort := flag.Int("end-port", 1024, "last port in the range to scan")
+	commonPortsOnly := flag.Bool("common-ports", false, "scan only the well-known common ports instead of --start-port/--end-port")
+	portsList := flag.String("ports", "", "comma-separated list of specific ports to scan, overrides --start-port/--end-port and --common-ports")
+	workers := flag.Int("workers", 100, "concurrent workers per host")
+	timeout := flag.Duration("timeout", 500*time.Millisecond, "dial timeout per port")
+	ratePerSec := flag.Float64("rate", 500, "max dials/sec across all hosts combined, to avoid tripping IDS")
+	output := flag.String("output", "", "write structured results to stdout: json, ndjson, or csv")
+	flag.Parse()
+
+	var writer ResultWriter
+	switch *output {
+	case "":
+		// No structured output requested, just the human-readable detail below
+	case "json":
+		writer = NewJSONWriter(os.Stdout)
+	case "ndjson":
+		writer = NewNDJSONWriter(os.Stdout)
+	case "csv":
+		writer = NewCSVWriter(os.Stdout)
+	default:
+		log.Fatalf("unknown --output %q, want json, ndjson, or csv", *output)
+	}
+
+	hostScanner, err := NewHostScanner(*target, *ratePerSec, *timeout, *workers)
+	if err != nil {
+		log.Fatal("Failed to resolve scan targets: ", err)
+	}
+
+	fmt.Printf("Workers: %d | Timeout: %v | Rate: %.0f/sec\n", *workers, *timeout, *ratePerSec)
+
+	var summaries []HostSummary
+	switch {
+	case *portsList != "":
+		ports, err := parsePorts(*portsList)
+		if err != nil {
+			log.Fatal("Invalid --ports: ", err)
+		}
+		fmt.Printf("Scanning %d host(s) on %d specific port(s)\n", len(hostScanner.hosts), len(ports))
+		summaries = hostScanner.ScanSpecificPorts(ctx, ports, writer)
+
+	case *commonPortsOnly:
+		fmt.Printf("Scanning %d host(s) on common ports\n", len(hostScanner.hosts))
+		summaries = hostScanner.ScanCommonPorts(ctx, writer)
+
+	default:
+		fmt.Printf("Scanning %d host(s) on ports %d-%d\n", len(hostScanner.hosts), *startPort, *endPort)
+		summaries = hostScanner.ScanRange(ctx, *startPort, *endPort, writer)
+	}
+
+	if writer != nil {
+		if err := writer.Close(); err != nil {
+			log.Println("Error writing results:", err)
+		}
+	}
+
+	PrintHostSummaries(summaries)
 }
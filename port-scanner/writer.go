@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// ResultWriter emits one host's scan results at a time, so a sweep can
+// be piped into other tools as it progresses instead of only at the end
+type ResultWriter interface {
+	WriteHost(host string, results []ScanResult) error
+	Close() error
+}
+
+// hostResult is the JSON/NDJSON shape for a single host's results
+type hostResult struct {
+	Host    string       `json:"host"`
+	Results []ScanResult `json:"results"`
+}
+
+// JSONWriter buffers every host's results and emits a single JSON
+// array document once the sweep finishes and Close is called
+type JSONWriter struct {
+	w     io.Writer
+	hosts []hostResult
+}
+
+func NewJSONWriter(w io.Writer) *JSONWriter {
+	return &JSONWriter{w: w}
+}
+
+func (jw *JSONWriter) WriteHost(host string, results []ScanResult) error {
+	jw.hosts = append(jw.hosts, hostResult{Host: host, Results: results})
+	return nil
+}
+
+func (jw *JSONWriter) Close() error {
+	encoder := json.NewEncoder(jw.w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(jw.hosts)
+}
+
+// NDJSONWriter emits one JSON object per host immediately, so a
+// downstream consumer can start processing before the sweep finishes
+type NDJSONWriter struct {
+	w io.Writer
+}
+
+func NewNDJSONWriter(w io.Writer) *NDJSONWriter {
+	return &NDJSONWriter{w: w}
+}
+
+func (nw *NDJSONWriter) WriteHost(host string, results []ScanResult) error {
+	return json.NewEncoder(nw.w).Encode(hostResult{Host: host, Results: results})
+}
+
+func (nw *NDJSONWriter) Close() error {
+	return nil
+}
+
+// CSVWriter emits one row per scanned port, prefixed with the host
+type CSVWriter struct {
+	w           *csv.Writer
+	wroteHeader bool
+}
+
+func NewCSVWriter(w io.Writer) *CSVWriter {
+	return &CSVWriter{w: csv.NewWriter(w)}
+}
+
+func (cw *CSVWriter) WriteHost(host string, results []ScanResult) error {
+	if !cw.wroteHeader {
+		if err := cw.w.Write([]string{"host", "port", "open", "service", "banner", "version"}); err != nil {
+			return err
+		}
+		cw.wroteHeader = true
+	}
+
+	for _, result := range results {
+		row := []string{
+			host,
+			fmt.Sprintf("%d", result.Port),
+			fmt.Sprintf("%t", result.Open),
+			result.Service,
+			result.Banner,
+			result.Version,
+		}
+		if err := cw.w.Write(row); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (cw *CSVWriter) Close() error {
+	cw.w.Flush()
+	return cw.w.Error()
+}